@@ -0,0 +1,15 @@
+package config
+
+import (
+	internalconfig "github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+)
+
+// Config is the process-wide configuration used to bootstrap main. It is a
+// thin alias over internal/config.Config so that cmd and internal packages
+// share a single source of truth for settings.
+type Config = internalconfig.Config
+
+// New parses Config from flags and environment variables.
+func New() *Config {
+	return internalconfig.New()
+}
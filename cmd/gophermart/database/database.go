@@ -0,0 +1,35 @@
+// Package database embeds and runs the SQL migrations that set up the
+// gophermart schema.
+package database
+
+import (
+	"embed"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// RunMigration applies every pending migration to the database at dsn and
+// returns the *migrate.Migrate instance used, in case the caller wants to
+// inspect the resulting version.
+func RunMigration(dsn string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return nil, err
+	}
+
+	return m, nil
+}
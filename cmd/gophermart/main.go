@@ -1,3 +1,9 @@
+// @title Gophermart Loyalty API
+// @version 1.0
+// @description Order registration, accrual tracking, and loyalty point withdrawal for the gophermart service.
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 package main
 
 import (
@@ -6,21 +12,29 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
 
 	"github.com/KokoulinM/go-musthave-diploma-tpl/cmd/gophermart/config"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/cmd/gophermart/database"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/accrual"
+	internalconfig "github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/database/postgres"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/handlers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/router"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/server"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/tasks"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/workers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/ws"
 )
 
 func main() {
-	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	cfg := config.New()
+
+	logger := zerolog.New(os.Stdout).Level(cfg.LogLevel)
 
 	logger.Log().Msg("starting server")
 
@@ -29,15 +43,8 @@ func main() {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
-	logger.Log().Msg("starting parse configuration")
-
-	cfg := config.New()
-
 	logger.Log().Msg("ServerAddress: " + cfg.ServerAddress)
 	logger.Log().Msg("AccrualSystemAddress: " + cfg.AccrualSystemAddress)
-	logger.Log().Msg("DataBase: " + cfg.DataBase.DataBaseURI)
-	logger.Log().Msg("AccessTokenSecret: " + cfg.Token.AccessTokenSecret)
-	logger.Log().Msg("RefreshTokenSecret: " + cfg.Token.RefreshTokenSecret)
 
 	db, err := sql.Open("postgres", cfg.DataBase.DataBaseURI)
 	if err != nil {
@@ -49,8 +56,35 @@ func main() {
 	repo := postgres.New(db)
 
 	jobStore := postgres.NewJobStore(db)
+
+	accrualClient := accrual.New(cfg.AccrualSystemAddress)
+
+	if cfg.AccrualMode == internalconfig.AccrualModePush || cfg.AccrualMode == internalconfig.AccrualModeHybrid {
+		registrationStore := postgres.NewRegistrationStore(db)
+
+		registration := models.AccrualRegistration{
+			SupervisionCallbackURL: cfg.ServerAddress + "/api/internal/accrual/supervise",
+			AccrualCallbackURL:     cfg.ServerAddress + "/api/internal/accrual/callback",
+			Statuses:               []string{"PROCESSING", "INVALID", "PROCESSED"},
+			UpdatedAt:              time.Now(),
+		}
+
+		if err := accrualClient.Register(ctx, registration); err != nil {
+			logger.Error().Msg(err.Error())
+		} else if err := registrationStore.Save(ctx, registration); err != nil {
+			logger.Error().Msg(err.Error())
+		}
+	}
+
 	var listTask []tasks.TaskInterface
-	listTask = append(listTask, tasks.NewCheckOrderStatusTask(cfg.AccrualSystemAddress, &logger, repo.ChangeOrderStatus))
+	listTask = append(listTask, tasks.NewCheckOrderStatusTask(
+		accrualClient,
+		cfg.AccrualMode,
+		time.Duration(cfg.AccrualStatusDeadline)*time.Second,
+		&logger,
+		repo.ChangeOrderStatus,
+		postgres.NewSilentOrdersFunc(db),
+	))
 	taskStore := tasks.NewTaskStore(listTask)
 
 	wp := workers.New(jobStore, taskStore, &cfg.WorkerPool, &logger)
@@ -68,9 +102,13 @@ func main() {
 
 	logger.Log().Msg("finish setup db")
 
-	handlers := handlers.New(repo, jobStore, &logger, cfg)
+	hub := ws.NewHub()
+
+	patStore := postgres.NewPersonalAccessTokenStore(db)
+
+	handlers := handlers.New(repo, jobStore, &logger, cfg, hub, patStore)
 
-	router := router.New(handlers, cfg)
+	router := router.New(handlers, cfg, patStore, &logger)
 
 	s := server.New(ctx, router, cfg.ServerAddress)
 
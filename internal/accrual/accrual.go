@@ -0,0 +1,122 @@
+// Package accrual is a thin client for the external accrual calculation
+// system: the service that tells gophermart how many bonus points an order
+// earned.
+package accrual
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+)
+
+// OrderStatus is the vocabulary of order-processing statuses the accrual
+// system itself uses. It does not always match gophermart's own order
+// statuses — see Order.GophermartStatus.
+type OrderStatus string
+
+const (
+	OrderStatusRegistered OrderStatus = "REGISTERED"
+	OrderStatusInvalid    OrderStatus = "INVALID"
+	OrderStatusProcessing OrderStatus = "PROCESSING"
+	OrderStatusProcessed  OrderStatus = "PROCESSED"
+)
+
+// Order is the accrual system's view of a single order, as returned by
+// Client.CheckOrder.
+type Order struct {
+	Number  string      `json:"order"`
+	Status  OrderStatus `json:"status"`
+	Accrual float64     `json:"accrual,omitempty"`
+}
+
+// GophermartStatus maps the accrual system's status vocabulary onto
+// gophermart's own order statuses, collapsing REGISTERED into NEW since
+// gophermart has no equivalent intermediate state.
+func (o Order) GophermartStatus() string {
+	if o.Status == OrderStatusRegistered {
+		return "NEW"
+	}
+
+	return string(o.Status)
+}
+
+// Client talks to the accrual system over HTTP.
+type Client struct {
+	address    string
+	httpClient *http.Client
+}
+
+// New builds a Client bound to the given accrual system address.
+func New(address string) *Client {
+	return &Client{
+		address:    address,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Register sends a producer-registration record describing where the
+// accrual system should push status updates. The accrual system is expected
+// to treat repeated registration for the same producer as an upsert, so it
+// is safe to call this on every startup.
+func (c *Client) Register(ctx context.Context, registration models.AccrualRegistration) error {
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("marshal registration: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"/api/internal/producers/register", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build registration request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send registration request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("registration rejected with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// CheckOrder asks the accrual system for number's current processing
+// status. It returns nil, nil if the accrual system has never heard of the
+// order (HTTP 204), which callers should treat as "still queued, try again
+// later".
+func (c *Client) CheckOrder(ctx context.Context, number string) (*Order, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.address+"/api/orders/"+number, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build order status request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send order status request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("order status request rejected with status %d", resp.StatusCode)
+	}
+
+	var order Order
+
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		return nil, fmt.Errorf("decode order status response: %w", err)
+	}
+
+	return &order, nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccrualMode selects how order statuses are refreshed from the accrual system.
+type AccrualMode string
+
+const (
+	AccrualModePoll   AccrualMode = "poll"
+	AccrualModePush   AccrualMode = "push"
+	AccrualModeHybrid AccrualMode = "hybrid"
+)
+
+type DataBase struct {
+	DataBaseURI string
+}
+
+type Token struct {
+	AccessTokenSecret  string
+	RefreshTokenSecret string
+}
+
+type WorkerPool struct {
+	WorkersCount int
+	QueueSize    int
+}
+
+// Security groups settings for credential protection that don't belong to
+// any single subsystem.
+type Security struct {
+	// TwoFactorEncryptionKey encrypts TOTP secrets at rest. It must be
+	// exactly 32 bytes (AES-256).
+	TwoFactorEncryptionKey string
+
+	// BcryptCost is the work factor used to hash user passwords.
+	BcryptCost int
+}
+
+// Config holds every setting the gophermart service needs at runtime.
+type Config struct {
+	ServerAddress        string
+	AccrualSystemAddress string
+	DataBase             DataBase
+	Token                Token
+	WorkerPool           WorkerPool
+	Security             Security
+
+	// AccrualMode controls whether order statuses are refreshed by polling the
+	// accrual system, by registering a push callback, or both (hybrid).
+	AccrualMode AccrualMode
+
+	// AccrualStatusDeadline is how long an order may go without an update in
+	// hybrid mode before it falls back to polling.
+	AccrualStatusDeadline int
+
+	// LogLevel controls the verbosity of the startup and request logs.
+	LogLevel zerolog.Level
+}
+
+// parseLogLevel maps the debug|info|warn|error level names accepted by the
+// -log-level flag and LOG_LEVEL environment variable to a zerolog.Level,
+// falling back to zerolog.InfoLevel for anything else.
+func parseLogLevel(level string) zerolog.Level {
+	switch level {
+	case "debug":
+		return zerolog.DebugLevel
+	case "warn":
+		return zerolog.WarnLevel
+	case "error":
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// parsed holds the Config built by the first call to New. flag.CommandLine
+// panics if a flag is defined twice, so later calls return a copy of it.
+var (
+	parsedMu sync.Mutex
+	parsed   *Config
+)
+
+// New parses Config from flags and environment variables, with environment
+// variables taking precedence over flags. Flags and environment are read on
+// the first call only; every later call returns a copy of that Config.
+func New() *Config {
+	parsedMu.Lock()
+	defer parsedMu.Unlock()
+
+	if parsed != nil {
+		cfg := *parsed
+
+		return &cfg
+	}
+
+	cfg := &Config{}
+
+	flag.StringVar(&cfg.ServerAddress, "a", "localhost:8080", "address and port to run server")
+	flag.StringVar(&cfg.AccrualSystemAddress, "r", "", "address of the accrual calculation system")
+	flag.StringVar(&cfg.DataBase.DataBaseURI, "d", "", "database connection URI")
+	flag.StringVar(&cfg.Token.AccessTokenSecret, "access-secret", "access-secret", "access token signing secret")
+	flag.StringVar(&cfg.Token.RefreshTokenSecret, "refresh-secret", "refresh-secret", "refresh token signing secret")
+	flag.IntVar(&cfg.WorkerPool.WorkersCount, "workers", 5, "number of background workers")
+	flag.IntVar(&cfg.WorkerPool.QueueSize, "queue-size", 100, "background job queue size")
+	accrualMode := flag.String("accrual-mode", string(AccrualModePoll), "accrual status sync strategy: poll|push|hybrid")
+	flag.IntVar(&cfg.AccrualStatusDeadline, "accrual-status-deadline", 300, "seconds an order may go silent in hybrid mode before polling resumes")
+	flag.StringVar(&cfg.Security.TwoFactorEncryptionKey, "totp-encryption-key", "totp-encryption-key-32-bytes!!!!", "32-byte key used to encrypt TOTP secrets at rest")
+	flag.IntVar(&cfg.Security.BcryptCost, "bcrypt-cost", bcrypt.DefaultCost, "bcrypt work factor used to hash user passwords")
+	logLevel := flag.String("log-level", "info", "log level: debug|info|warn|error")
+
+	flag.Parse()
+
+	cfg.AccrualMode = AccrualMode(*accrualMode)
+	cfg.LogLevel = parseLogLevel(*logLevel)
+
+	if envServerAddress := os.Getenv("RUN_ADDRESS"); envServerAddress != "" {
+		cfg.ServerAddress = envServerAddress
+	}
+
+	if envAccrualAddress := os.Getenv("ACCRUAL_SYSTEM_ADDRESS"); envAccrualAddress != "" {
+		cfg.AccrualSystemAddress = envAccrualAddress
+	}
+
+	if envDataBaseURI := os.Getenv("DATABASE_URI"); envDataBaseURI != "" {
+		cfg.DataBase.DataBaseURI = envDataBaseURI
+	}
+
+	if envAccessSecret := os.Getenv("ACCESS_TOKEN_SECRET"); envAccessSecret != "" {
+		cfg.Token.AccessTokenSecret = envAccessSecret
+	}
+
+	if envRefreshSecret := os.Getenv("REFRESH_TOKEN_SECRET"); envRefreshSecret != "" {
+		cfg.Token.RefreshTokenSecret = envRefreshSecret
+	}
+
+	if envAccrualMode := os.Getenv("ACCRUAL_MODE"); envAccrualMode != "" {
+		cfg.AccrualMode = AccrualMode(envAccrualMode)
+	}
+
+	if envTOTPKey := os.Getenv("TOTP_ENCRYPTION_KEY"); envTOTPKey != "" {
+		cfg.Security.TwoFactorEncryptionKey = envTOTPKey
+	}
+
+	if envBcryptCost := os.Getenv("BCRYPT_COST"); envBcryptCost != "" {
+		if cost, err := strconv.Atoi(envBcryptCost); err == nil {
+			cfg.Security.BcryptCost = cost
+		}
+	}
+
+	if envLogLevel := os.Getenv("LOG_LEVEL"); envLogLevel != "" {
+		cfg.LogLevel = parseLogLevel(envLogLevel)
+	}
+
+	switch cfg.AccrualMode {
+	case AccrualModePoll, AccrualModePush, AccrualModeHybrid:
+	default:
+		cfg.AccrualMode = AccrualModePoll
+	}
+
+	stored := *cfg
+	parsed = &stored
+
+	return cfg
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  zerolog.Level
+	}{
+		{level: "debug", want: zerolog.DebugLevel},
+		{level: "info", want: zerolog.InfoLevel},
+		{level: "warn", want: zerolog.WarnLevel},
+		{level: "error", want: zerolog.ErrorLevel},
+		{level: "nonsense", want: zerolog.InfoLevel},
+		{level: "", want: zerolog.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseLogLevel(tt.level))
+		})
+	}
+}
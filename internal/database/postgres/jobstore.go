@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// JobStore queues order numbers for the background accrual status checks
+// that internal/workers.WorkerPool drains on each tick.
+type JobStore struct {
+	db *sql.DB
+}
+
+// NewJobStore builds a JobStore backed by db.
+func NewJobStore(db *sql.DB) *JobStore {
+	return &JobStore{db: db}
+}
+
+// AddJob enqueues orderNumber for a background accrual status check.
+func (s *JobStore) AddJob(ctx context.Context, orderNumber string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO jobs (order_number) VALUES ($1)
+	`, orderNumber)
+
+	return err
+}
+
+// NextJobs dequeues up to limit pending jobs, oldest first, deleting them
+// from the queue as part of the same transaction so a crashed worker can
+// never cause a job to be processed twice by two different instances.
+func (s *JobStore) NextJobs(ctx context.Context, limit int) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, order_number FROM jobs
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	var orderNumbers []string
+
+	for rows.Next() {
+		var id int64
+		var orderNumber string
+
+		if err := rows.Scan(&id, &orderNumber); err != nil {
+			rows.Close()
+
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		orderNumbers = append(orderNumbers, orderNumber)
+	}
+
+	if err := rows.Err(); err != nil {
+		rows.Close()
+
+		return nil, err
+	}
+
+	rows.Close()
+
+	if len(ids) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM jobs WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+	}
+
+	return orderNumbers, tx.Commit()
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+)
+
+// PersonalAccessTokenStore persists personal access tokens by their sha256
+// hash; the plaintext token is never stored.
+type PersonalAccessTokenStore struct {
+	db *sql.DB
+}
+
+// NewPersonalAccessTokenStore builds a PersonalAccessTokenStore backed by db.
+func NewPersonalAccessTokenStore(db *sql.DB) *PersonalAccessTokenStore {
+	return &PersonalAccessTokenStore{db: db}
+}
+
+// Create inserts token and returns the ID the database generated for it.
+func (s *PersonalAccessTokenStore) Create(ctx context.Context, token models.PersonalAccessToken) (string, error) {
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, token.UserID, token.Name, token.TokenHash, scopes, token.ExpiresAt).Scan(&id)
+
+	return id, err
+}
+
+// List returns every personal access token belonging to userID, most
+// recently created first. TokenHash is never populated.
+func (s *PersonalAccessTokenStore) List(ctx context.Context, userID string) ([]models.PersonalAccessToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, scopes, created_at, last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.PersonalAccessToken
+
+	for rows.Next() {
+		token := models.PersonalAccessToken{UserID: userID}
+
+		var scopes []byte
+
+		if err := rows.Scan(&token.ID, &token.Name, &scopes, &token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(scopes, &token.Scopes); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Revoke deletes the token with the given ID, scoped to userID so a user
+// can never revoke another user's token.
+func (s *PersonalAccessTokenStore) Revoke(ctx context.Context, userID, tokenID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2
+	`, tokenID, userID)
+
+	return err
+}
+
+// FindByHash looks up the token with the given sha256 hash, or nil if none
+// exists. It is the lookup the auth middleware performs on every request
+// bearing a PAT.
+func (s *PersonalAccessTokenStore) FindByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{TokenHash: hash}
+
+	var scopes []byte
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, scopes, created_at, last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1
+	`, hash).Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(scopes, &token.Scopes); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// TouchLastUsed stamps the token with the given hash as used just now. It is
+// called asynchronously by the auth middleware so that recording usage
+// never adds latency to the request it authenticated.
+func (s *PersonalAccessTokenStore) TouchLastUsed(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = now() WHERE token_hash = $1
+	`, hash)
+
+	return err
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+)
+
+// RegistrationStore persists the producer-registration record we hand to the
+// accrual system. Saving is an upsert of a single row, so re-registering on
+// restart is idempotent. main re-sends the registration on every startup and
+// does not compare it with the stored one first.
+type RegistrationStore struct {
+	db *sql.DB
+}
+
+// NewRegistrationStore builds a RegistrationStore backed by db.
+func NewRegistrationStore(db *sql.DB) *RegistrationStore {
+	return &RegistrationStore{db: db}
+}
+
+// Save upserts the single current registration row. There is only ever one
+// row, keyed by a fixed id, because a gophermart instance has exactly one
+// registration with the accrual system at a time.
+func (s *RegistrationStore) Save(ctx context.Context, registration models.AccrualRegistration) error {
+	statuses, err := json.Marshal(registration.Statuses)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO accrual_registrations (id, supervision_callback_url, accrual_callback_url, statuses, updated_at)
+		VALUES (1, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			supervision_callback_url = EXCLUDED.supervision_callback_url,
+			accrual_callback_url = EXCLUDED.accrual_callback_url,
+			statuses = EXCLUDED.statuses,
+			updated_at = EXCLUDED.updated_at
+	`, registration.SupervisionCallbackURL, registration.AccrualCallbackURL, statuses, registration.UpdatedAt)
+
+	return err
+}
+
+// Get returns the last registration we saved, or nil if we have never
+// registered.
+func (s *RegistrationStore) Get(ctx context.Context) (*models.AccrualRegistration, error) {
+	var registration models.AccrualRegistration
+	var statuses []byte
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT supervision_callback_url, accrual_callback_url, statuses, updated_at
+		FROM accrual_registrations
+		WHERE id = 1
+	`).Scan(&registration.SupervisionCallbackURL, &registration.AccrualCallbackURL, &statuses, &registration.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(statuses, &registration.Statuses); err != nil {
+		return nil, err
+	}
+
+	return &registration, nil
+}
@@ -0,0 +1,323 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/handlers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/password"
+)
+
+// uniqueViolationCode is the SQLSTATE lib/pq reports for a unique
+// constraint violation.
+const uniqueViolationCode = "23505"
+
+// Repository is the Postgres-backed implementation of handlers.Repository.
+type Repository struct {
+	db *sql.DB
+}
+
+// New builds a Repository backed by db.
+func New(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+// reported by the Postgres driver.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
+
+// CreateUser inserts user, whose Password the caller is expected to have
+// already hashed. It returns a handlers.ErrorWithDB tagged "UniqConstraint"
+// if the login is already taken.
+func (r *Repository) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO users (login, password)
+		VALUES ($1, $2)
+		RETURNING id
+	`, user.Login, user.Password).Scan(&user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, handlers.NewErrorWithDB(err, "UniqConstraint")
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CheckPassword looks up the user by login and verifies user.Password
+// against the stored hash. A stored value that predates password hashing is
+// compared directly and transparently re-saved as a bcrypt hash on success.
+func (r *Repository) CheckPassword(ctx context.Context, user models.User) (*models.User, error) {
+	var stored models.User
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, login, password FROM users WHERE login = $1
+	`, user.Login).Scan(&stored.ID, &stored.Login, &stored.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if password.NeedsRehash(stored.Password) {
+		if stored.Password != user.Password {
+			return nil, errors.New("invalid credentials")
+		}
+
+		hash, err := password.Hash(0, user.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := r.db.ExecContext(ctx, `UPDATE users SET password = $1 WHERE id = $2`, hash, stored.ID); err != nil {
+			return nil, err
+		}
+
+		stored.Password = hash
+
+		return &stored, nil
+	}
+
+	if !password.Verify(stored.Password, user.Password) {
+		return nil, errors.New("invalid credentials")
+	}
+
+	return &stored, nil
+}
+
+// CreateOrder registers order.Number for order.UserID. It returns a
+// handlers.ErrorWithDB tagged "OrderAlreadyRegisterByYou" if order.UserID
+// already submitted this order number, or "OrderAlreadyRegister" if a
+// different user did.
+func (r *Repository) CreateOrder(ctx context.Context, order models.Order) error {
+	var existingUserID string
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id FROM orders WHERE number = $1
+	`, order.Number).Scan(&existingUserID)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO orders (number, user_id, status)
+			VALUES ($1, $2, $3)
+		`, order.Number, order.UserID, order.Status)
+
+		return err
+	case err != nil:
+		return err
+	case existingUserID == order.UserID:
+		return handlers.NewErrorWithDB(errors.New("order already registered by this user"), "OrderAlreadyRegisterByYou")
+	default:
+		return handlers.NewErrorWithDB(errors.New("order already registered by another user"), "OrderAlreadyRegister")
+	}
+}
+
+// GetOrders returns userID's orders, oldest first.
+func (r *Repository) GetOrders(ctx context.Context, userID string) ([]models.ResponseOrderWithAccrual, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT number, status, accrual, uploaded_at
+		FROM orders
+		WHERE user_id = $1
+		ORDER BY uploaded_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.ResponseOrderWithAccrual
+
+	for rows.Next() {
+		var order models.ResponseOrderWithAccrual
+
+		if err := rows.Scan(&order.Number, &order.Status, &order.Accrual, &order.UploadedAt); err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// currentBalance returns userID's current spendable balance: accrued points
+// minus what they have already withdrawn.
+func currentBalance(ctx context.Context, q querier, userID string) (float64, error) {
+	var current float64
+
+	err := q.QueryRowContext(ctx, `
+		SELECT
+			COALESCE((SELECT SUM(accrual) FROM orders WHERE user_id = $1), 0) -
+			COALESCE((SELECT SUM(sum) FROM withdrawals WHERE user_id = $1), 0)
+	`, userID).Scan(&current)
+
+	return current, err
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, letting currentBalance
+// run either standalone or as part of CreateWithdraw's transaction.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GetBalance returns userID's current and withdrawn balance.
+func (r *Repository) GetBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	current, err := currentBalance(ctx, r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawn float64
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(sum), 0) FROM withdrawals WHERE user_id = $1
+	`, userID).Scan(&withdrawn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Balance{Current: current, Withdrawn: withdrawn}, nil
+}
+
+// CreateWithdraw records a withdrawal against userID's balance, checking the
+// balance and inserting the row in the same transaction. It returns a
+// handlers.ErrorWithDB tagged "InsufficientFunds" if the balance is too low.
+func (r *Repository) CreateWithdraw(ctx context.Context, withdraw models.Withdraw) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := currentBalance(ctx, tx, withdraw.UserID)
+	if err != nil {
+		return err
+	}
+
+	if current < withdraw.Sum {
+		return handlers.NewErrorWithDB(errors.New("insufficient funds"), "InsufficientFunds")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO withdrawals (order_number, user_id, sum)
+		VALUES ($1, $2, $3)
+	`, withdraw.Order, withdraw.UserID, withdraw.Sum); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetWithdrawals returns userID's withdrawals, oldest first.
+func (r *Repository) GetWithdrawals(ctx context.Context, userID string) ([]models.Withdraw, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_number, sum, processed_at
+		FROM withdrawals
+		WHERE user_id = $1
+		ORDER BY processed_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []models.Withdraw
+
+	for rows.Next() {
+		withdraw := models.Withdraw{UserID: userID}
+
+		if err := rows.Scan(&withdraw.Order, &withdraw.Sum, &withdraw.ProcessedAt); err != nil {
+			return nil, err
+		}
+
+		withdrawals = append(withdrawals, withdraw)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+// ChangeOrderStatus persists number's new status and accrual and returns the
+// ID of the user it belongs to.
+func (r *Repository) ChangeOrderStatus(ctx context.Context, number string, status string, accrual float64) (string, error) {
+	var userID string
+
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE orders
+		SET status = $2, accrual = $3, updated_at = now()
+		WHERE number = $1
+		RETURNING user_id
+	`, number, status, accrual).Scan(&userID)
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// SaveTwoFactorSecret upserts userID's pending TOTP enrollment, always
+// unconfirmed, replacing any prior one.
+func (r *Repository) SaveTwoFactorSecret(ctx context.Context, userID string, encryptedSecret string, recoveryCodes []string) error {
+	codes, err := json.Marshal(recoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO user_two_factors (user_id, secret, recovery_codes, enabled)
+		VALUES ($1, $2, $3, false)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			recovery_codes = EXCLUDED.recovery_codes,
+			enabled = false
+	`, userID, encryptedSecret, codes)
+
+	return err
+}
+
+// GetTwoFactor returns userID's 2FA enrollment, or nil if they have never
+// enrolled.
+func (r *Repository) GetTwoFactor(ctx context.Context, userID string) (*models.TwoFactor, error) {
+	twoFactor := &models.TwoFactor{UserID: userID}
+
+	var codes []byte
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT secret, recovery_codes, enabled FROM user_two_factors WHERE user_id = $1
+	`, userID).Scan(&twoFactor.EncryptedSecret, &codes, &twoFactor.Enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(codes, &twoFactor.RecoveryCodes); err != nil {
+		return nil, err
+	}
+
+	return twoFactor, nil
+}
+
+// ActivateTwoFactor confirms userID's pending 2FA enrollment.
+func (r *Repository) ActivateTwoFactor(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_two_factors SET enabled = true WHERE user_id = $1`, userID)
+
+	return err
+}
+
+// DisableTwoFactor removes userID's 2FA enrollment entirely.
+func (r *Repository) DisableTwoFactor(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_two_factors WHERE user_id = $1`, userID)
+
+	return err
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/password"
+)
+
+// TestRepository_CheckPassword_Rehash covers the legacy path: a user whose
+// stored password predates bcrypt hashing is authenticated by direct
+// comparison, then transparently rehashed and persisted.
+func TestRepository_CheckPassword_Rehash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := New(db)
+
+	mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		WithArgs("login").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password"}).
+			AddRow("userID", "login", "12345"))
+
+	mock.ExpectExec(`UPDATE users SET password = \$1 WHERE id = \$2`).
+		WithArgs(sqlmock.AnyArg(), "userID").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	user, err := repo.CheckPassword(context.Background(), models.User{Login: "login", Password: "12345"})
+	assert.NoError(t, err)
+	assert.Equal(t, "userID", user.ID)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("12345")))
+	assert.False(t, password.NeedsRehash(user.Password))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_CheckPassword_RehashWrongPassword covers a legacy stored
+// password that doesn't match what was supplied: it must fail without ever
+// rehashing or persisting anything.
+func TestRepository_CheckPassword_RehashWrongPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := New(db)
+
+	mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		WithArgs("login").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password"}).
+			AddRow("userID", "login", "12345"))
+
+	_, err = repo.CheckPassword(context.Background(), models.User{Login: "login", Password: "wrong"})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_CheckPassword_Bcrypt covers an already-hashed stored
+// password: it is compared with bcrypt and never rehashed.
+func TestRepository_CheckPassword_Bcrypt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := New(db)
+
+	hash, err := password.Hash(bcrypt.MinCost, "12345")
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		WithArgs("login").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password"}).
+			AddRow("userID", "login", hash))
+
+	user, err := repo.CheckPassword(context.Background(), models.User{Login: "login", Password: "12345"})
+	assert.NoError(t, err)
+	assert.Equal(t, hash, user.Password)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/tasks"
+)
+
+// NewSilentOrdersFunc builds a tasks.SilentOrdersFunc backed by db. With a
+// zero deadline it returns every order still awaiting a final status; with a
+// positive deadline it returns only the ones that have not been updated
+// within that window, which is what hybrid accrual mode polls for.
+func NewSilentOrdersFunc(db *sql.DB) tasks.SilentOrdersFunc {
+	return func(ctx context.Context, deadline time.Duration) ([]string, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT number FROM orders
+			WHERE status IN ('NEW', 'PROCESSING')
+			AND ($1::interval = '0' OR updated_at < now() - $1::interval)
+		`, deadline.String())
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var numbers []string
+
+		for rows.Next() {
+			var number string
+			if err := rows.Scan(&number); err != nil {
+				return nil, err
+			}
+
+			numbers = append(numbers, number)
+		}
+
+		return numbers, rows.Err()
+	}
+}
@@ -0,0 +1,46 @@
+// Package docs serves the generated OpenAPI 3 spec for the gophermart API
+// and a Swagger UI page to browse it, both embedded into the binary so
+// serving them needs no files on disk at runtime.
+//
+// openapi.json is generated by `make docs` from the @Summary/@Router
+// annotations on the handlers in internal/handlers; regenerate it whenever
+// those annotations change so the served spec never drifts from the code.
+package docs
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed openapi.json
+var spec embed.FS
+
+//go:embed swagger-ui
+var ui embed.FS
+
+// SpecHandler serves the generated OpenAPI 3 document as JSON.
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	data, err := spec.ReadFile("openapi.json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Write(data)
+}
+
+// UIHandler serves the embedded Swagger UI, rooted at pathPrefix (e.g.
+// "/swagger/"). The page itself is embedded, but it loads the swagger-ui-dist
+// JS/CSS bundle from a CDN at view time rather than vendoring it.
+func UIHandler(pathPrefix string) http.Handler {
+	assets, err := fs.Sub(ui, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.StripPrefix(pathPrefix, http.FileServer(http.FS(assets)))
+}
@@ -0,0 +1,23 @@
+package handlers
+
+// ErrorWithDB wraps a storage error with a short, machine-readable code
+// describing the kind of failure (a specific constraint violation, for
+// example), so handlers can choose the right HTTP status without depending
+// on database/sql or the Postgres driver directly.
+type ErrorWithDB struct {
+	err  error
+	Code string
+}
+
+// NewErrorWithDB wraps err, tagging it with code.
+func NewErrorWithDB(err error, code string) *ErrorWithDB {
+	return &ErrorWithDB{err: err, Code: code}
+}
+
+func (e *ErrorWithDB) Error() string {
+	return e.err.Error()
+}
+
+func (e *ErrorWithDB) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,939 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/password"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/pat"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/token"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/twofactor"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/ws"
+)
+
+//go:generate mockgen -source=handlers.go -destination=mock_handlers.go -package=handlers
+
+type ctxKey string
+
+// UserIDCtx is the context key handlers use to read the authenticated
+// user's ID, populated by the JWT middleware.
+const UserIDCtx ctxKey = "userID"
+
+// PATScopesCtx is the context key holding the scopes granted to the
+// request, populated by the auth middleware when the caller authenticated
+// with a personal access token. It is absent for JWT-authenticated
+// requests, which are never scope-restricted.
+const PATScopesCtx ctxKey = "patScopes"
+
+// Repository is the persistence boundary handlers depend on.
+type Repository interface {
+	// CreateUser persists user as given. Callers hash user.Password with
+	// internal/password first, so it never reaches storage in plaintext.
+	CreateUser(ctx context.Context, user models.User) (*models.User, error)
+	// CheckPassword verifies user.Password against the stored hash and
+	// returns the stored user on success. If the stored value predates
+	// password hashing (password.NeedsRehash), it is compared directly and
+	// then transparently re-saved as a bcrypt hash.
+	CheckPassword(ctx context.Context, user models.User) (*models.User, error)
+	CreateOrder(ctx context.Context, order models.Order) error
+	GetOrders(ctx context.Context, userID string) ([]models.ResponseOrderWithAccrual, error)
+	GetBalance(ctx context.Context, userID string) (*models.Balance, error)
+	CreateWithdraw(ctx context.Context, withdraw models.Withdraw) error
+	GetWithdrawals(ctx context.Context, userID string) ([]models.Withdraw, error)
+	// ChangeOrderStatus persists a new status (and accrual, when present) for
+	// an order and returns the ID of the user it belongs to, so callers can
+	// notify that user's WebSocket subscribers.
+	ChangeOrderStatus(ctx context.Context, number string, status string, accrual float64) (string, error)
+	// SaveTwoFactorSecret stores a freshly-enrolled, not-yet-activated TOTP
+	// secret and its recovery codes, replacing any prior unconfirmed
+	// enrollment for the user.
+	SaveTwoFactorSecret(ctx context.Context, userID string, encryptedSecret string, recoveryCodes []string) error
+	// GetTwoFactor returns userID's 2FA enrollment, or nil if they have
+	// never enrolled.
+	GetTwoFactor(ctx context.Context, userID string) (*models.TwoFactor, error)
+	// ActivateTwoFactor marks userID's pending enrollment as confirmed, so
+	// Login starts requiring the 2FA challenge for them.
+	ActivateTwoFactor(ctx context.Context, userID string) error
+	// DisableTwoFactor removes userID's 2FA enrollment entirely.
+	DisableTwoFactor(ctx context.Context, userID string) error
+}
+
+// JobStore queues order numbers for background accrual status checks.
+type JobStore interface {
+	AddJob(ctx context.Context, orderNumber string) error
+}
+
+// PersonalAccessTokenStore persists personal access tokens for the
+// /api/user/tokens endpoints. It is satisfied by
+// postgres.PersonalAccessTokenStore.
+type PersonalAccessTokenStore interface {
+	Create(ctx context.Context, token models.PersonalAccessToken) (string, error)
+	List(ctx context.Context, userID string) ([]models.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID, tokenID string) error
+}
+
+// Handlers implements the loyalty system HTTP API.
+type Handlers struct {
+	repo     Repository
+	jobStore JobStore
+	logger   *zerolog.Logger
+	cfg      *config.Config
+	hub      *ws.Hub
+	patStore PersonalAccessTokenStore
+}
+
+// New builds Handlers backed by the given repository and job store. hub
+// fans order and balance change events out to connected WebSocket clients;
+// patStore backs the personal access token endpoints.
+func New(repo Repository, jobStore JobStore, logger *zerolog.Logger, cfg *config.Config, hub *ws.Hub, patStore PersonalAccessTokenStore) *Handlers {
+	return &Handlers{
+		repo:     repo,
+		jobStore: jobStore,
+		logger:   logger,
+		cfg:      cfg,
+		hub:      hub,
+		patStore: patStore,
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// credentialsRequest is the body Register and Login both accept.
+type credentialsRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// readJSONBody reads r.Body fully and unmarshals it into v. It returns false
+// and has already written a 400 if the body was empty or unreadable, or a 500
+// if the body was non-empty but failed to unmarshal; callers should only
+// proceed on true.
+func readJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return false
+	}
+
+	return true
+}
+
+// Register creates a new user and, on success, logs them in immediately by
+// returning a fresh access/refresh token pair.
+//
+// @Summary Register a new user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body credentialsRequest true "login and password"
+// @Success 200 {object} models.TokenPair
+// @Failure 400
+// @Failure 409
+// @Failure 500
+// @Router /api/user/register [post]
+func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+
+	if !readJSONBody(w, r, &req) {
+		return
+	}
+
+	hash, err := password.Hash(h.cfg.Security.BcryptCost, req.Password)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	user, err := h.repo.CreateUser(r.Context(), models.User{Login: req.Login, Password: hash})
+
+	var dbErr *ErrorWithDB
+
+	switch {
+	case err == nil:
+		h.issueTokenPair(w, user.ID)
+	case errors.As(err, &dbErr) && dbErr.Code == "UniqConstraint":
+		w.WriteHeader(http.StatusConflict)
+	default:
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Login authenticates an existing user and returns a fresh access/refresh
+// token pair. If the user has 2FA enabled, it instead returns a partial
+// token that only ChallengeTwoFactor will accept, and the caller must
+// complete the TOTP challenge to obtain a full token pair.
+//
+// @Summary Authenticate an existing user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body credentialsRequest true "login and password"
+// @Success 200 {object} models.TokenPair "full token pair, or a partial token if 2FA is enabled"
+// @Failure 400
+// @Failure 409
+// @Failure 500
+// @Router /api/user/login [post]
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+
+	if !readJSONBody(w, r, &req) {
+		return
+	}
+
+	user, err := h.repo.CheckPassword(r.Context(), models.User{Login: req.Login, Password: req.Password})
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+
+		return
+	}
+
+	twoFactor, err := h.repo.GetTwoFactor(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if twoFactor != nil && twoFactor.Enabled {
+		h.issueToken(w, user.ID, token.ScopeTwoFactorPending)
+
+		return
+	}
+
+	h.issueTokenPair(w, user.ID)
+}
+
+// issueToken mints a single token of the given scope for userID and writes
+// it as the JSON response body, with RefreshToken left empty.
+func (h *Handlers) issueToken(w http.ResponseWriter, userID string, scope token.Scope) {
+	accessToken, err := token.Issue(h.cfg.Token.AccessTokenSecret, userID, scope)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(models.TokenPair{
+		AccessToken: accessToken,
+	})
+}
+
+// issueTokenPair mints a full access/refresh token pair for userID and
+// writes it as the JSON response body.
+func (h *Handlers) issueTokenPair(w http.ResponseWriter, userID string) {
+	accessToken, err := token.Issue(h.cfg.Token.AccessTokenSecret, userID, token.ScopeFull)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	refreshToken, err := token.Issue(h.cfg.Token.RefreshTokenSecret, userID, token.ScopeFull)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// isValidOrderNumber reports whether number consists only of digits and
+// passes the Luhn check digit algorithm required of order numbers.
+func isValidOrderNumber(number string) bool {
+	if number == "" {
+		return false
+	}
+
+	sum := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+
+		digit := int(c - '0')
+
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// CreateOrder registers number as an order submitted by the authenticated
+// user for accrual processing and queues it for a background status check.
+//
+// @Summary Submit an order number for accrual processing
+// @Tags orders
+// @Security BearerAuth
+// @Accept plain
+// @Param number body string true "order number"
+// @Success 202
+// @Success 200 "already submitted by this user"
+// @Failure 409 "already submitted by another user"
+// @Failure 422 "order number fails the Luhn check"
+// @Router /api/user/orders [post]
+func (h *Handlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	number := strings.TrimSpace(string(body))
+
+	if !isValidOrderNumber(number) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	err = h.repo.CreateOrder(r.Context(), models.Order{
+		UserID: userID,
+		Number: number,
+		Status: "NEW",
+	})
+
+	var dbErr *ErrorWithDB
+
+	switch {
+	case err == nil:
+		if err := h.jobStore.AddJob(r.Context(), number); err != nil {
+			h.logger.Error().Msg(err.Error())
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	case errors.As(err, &dbErr) && dbErr.Code == "OrderAlreadyRegisterByYou":
+		w.WriteHeader(http.StatusOK)
+	case errors.As(err, &dbErr) && dbErr.Code == "OrderAlreadyRegister":
+		w.WriteHeader(http.StatusConflict)
+	default:
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// GetOrders returns every order the authenticated user has submitted.
+//
+// @Summary List the authenticated user's submitted orders
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.ResponseOrderWithAccrual
+// @Success 204 "no orders yet"
+// @Failure 500
+// @Router /api/user/orders [get]
+func (h *Handlers) GetOrders(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	orders, err := h.repo.GetOrders(r.Context(), userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if len(orders) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GetBalance returns the authenticated user's current and withdrawn
+// accrual balance.
+//
+// @Summary Get the authenticated user's current and withdrawn balance
+// @Tags balance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.Balance
+// @Failure 500
+// @Router /api/user/balance [get]
+func (h *Handlers) GetBalance(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	balance, err := h.repo.GetBalance(r.Context(), userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(balance)
+}
+
+// withdrawRequest is the body CreateWithdraw accepts.
+type withdrawRequest struct {
+	Order string  `json:"order"`
+	Sum   float64 `json:"sum"`
+}
+
+// CreateWithdraw spends points from the authenticated user's balance against
+// an order number.
+//
+// @Summary Spend loyalty points against an order
+// @Tags balance
+// @Security BearerAuth
+// @Accept json
+// @Param request body withdrawRequest true "order number and amount to withdraw"
+// @Success 200
+// @Failure 402 "insufficient balance"
+// @Failure 422 "order number fails the Luhn check"
+// @Router /api/user/balance/withdraw [post]
+func (h *Handlers) CreateWithdraw(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	var req withdrawRequest
+
+	if !readJSONBody(w, r, &req) {
+		return
+	}
+
+	if !isValidOrderNumber(req.Order) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	err := h.repo.CreateWithdraw(r.Context(), models.Withdraw{
+		UserID: userID,
+		Order:  req.Order,
+		Sum:    req.Sum,
+	})
+
+	var dbErr *ErrorWithDB
+
+	switch {
+	case err == nil:
+		h.hub.Publish(userID, "balance_changed", req)
+
+		w.WriteHeader(http.StatusOK)
+	case errors.As(err, &dbErr) && dbErr.Code == "InsufficientFunds":
+		w.WriteHeader(http.StatusPaymentRequired)
+	default:
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// GetWithdrawals returns every withdrawal the authenticated user has made.
+//
+// @Summary List the authenticated user's withdrawals
+// @Tags balance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Withdraw
+// @Success 204 "no withdrawals yet"
+// @Failure 500
+// @Router /api/user/balance/withdrawals [get]
+func (h *Handlers) GetWithdrawals(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	withdrawals, err := h.repo.GetWithdrawals(r.Context(), userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if len(withdrawals) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(withdrawals)
+}
+
+// Subscribe upgrades /api/user/events to a WebSocket connection and streams
+// the authenticated user's order and balance change events to it until the
+// connection closes. Order status events are published only for updates
+// received through AccrualCallback; status changes found by the polling task
+// are not published. Balance events are published by CreateWithdraw.
+//
+// @Summary Subscribe to real-time order and balance events
+// @Tags realtime
+// @Security BearerAuth
+// @Success 101
+// @Router /api/user/events [get]
+func (h *Handlers) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+
+		return
+	}
+
+	client := ws.NewClient(conn, h.logger)
+	unsubscribe := h.hub.Subscribe(userID, client)
+	defer unsubscribe()
+
+	go client.WritePump()
+
+	client.ReadPump()
+}
+
+// AccrualCallback receives push notifications from the accrual system about
+// an order's status and persists them directly, bypassing the polling task.
+// It backs the callback URL handed to the accrual system in the producer
+// registration record.
+//
+// @Summary Receive a push notification about an order's status
+// @Tags accrual
+// @Accept json
+// @Param update body models.AccrualStatusUpdate true "order status update"
+// @Success 200
+// @Failure 400
+// @Failure 500
+// @Router /api/internal/accrual/callback [post]
+func (h *Handlers) AccrualCallback(w http.ResponseWriter, r *http.Request) {
+	var update models.AccrualStatusUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	userID, err := h.repo.ChangeOrderStatus(r.Context(), update.Order, update.Status, update.Accrual)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	h.hub.Publish(userID, "order_status_changed", update)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// twoFactorCodeRequest is the body VerifyTwoFactor, DisableTwoFactor, and
+// ChallengeTwoFactor all accept.
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnrollTwoFactor provisions a new, not-yet-active TOTP secret and recovery
+// codes for the authenticated user and returns the otpauth:// URI and QR
+// code to scan into an authenticator app. 2FA only takes effect once the
+// user confirms a code via VerifyTwoFactor.
+//
+// @Summary Start TOTP enrollment
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorEnrollment
+// @Failure 500
+// @Router /api/user/2fa/enroll [post]
+func (h *Handlers) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	enrollment, err := twofactor.Generate(userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	encryptedSecret, err := twofactor.Encrypt(enrollment.Secret, []byte(h.cfg.Security.TwoFactorEncryptionKey))
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if err := h.repo.SaveTwoFactorSecret(r.Context(), userID, encryptedSecret, enrollment.RecoveryCodes); err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	qrCodePNG, err := twofactor.QRCodePNG(enrollment.OTPAuthURL)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(models.TwoFactorEnrollment{
+		OTPAuthURL:    enrollment.OTPAuthURL,
+		QRCodePNG:     qrCodePNG,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	})
+}
+
+// VerifyTwoFactor confirms a pending EnrollTwoFactor enrollment with a TOTP
+// code, activating 2FA for the authenticated user.
+//
+// @Summary Confirm a pending TOTP enrollment
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Param request body twoFactorCodeRequest true "TOTP code"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Router /api/user/2fa/verify [post]
+func (h *Handlers) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	var req twoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if !h.checkTwoFactorCode(w, r.Context(), userID, req.Code) {
+		return
+	}
+
+	if err := h.repo.ActivateTwoFactor(r.Context(), userID); err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DisableTwoFactor removes the authenticated user's 2FA enrollment after
+// confirming a current TOTP code.
+//
+// @Summary Disable TOTP 2FA
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Param request body twoFactorCodeRequest true "TOTP code"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Router /api/user/2fa/disable [post]
+func (h *Handlers) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	var req twoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if !h.checkTwoFactorCode(w, r.Context(), userID, req.Code) {
+		return
+	}
+
+	if err := h.repo.DisableTwoFactor(r.Context(), userID); err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ChallengeTwoFactor exchanges a partial-auth token (issued by Login to a
+// user with 2FA enabled) plus a valid TOTP code for a full access/refresh
+// token pair. It authenticates the caller itself, since the Authenticate
+// middleware rejects partial-auth tokens outright.
+//
+// @Summary Complete login by verifying a TOTP code
+// @Tags auth
+// @Security PartialBearerAuth
+// @Accept json
+// @Produce json
+// @Param request body twoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.TokenPair
+// @Failure 400
+// @Failure 401
+// @Router /api/user/2fa/challenge [post]
+func (h *Handlers) ChallengeTwoFactor(w http.ResponseWriter, r *http.Request) {
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	claims, err := token.Parse(h.cfg.Token.AccessTokenSecret, tokenString)
+	if err != nil || claims.Scope != token.ScopeTwoFactorPending {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	var req twoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if !h.checkTwoFactorCode(w, r.Context(), claims.UserID, req.Code) {
+		return
+	}
+
+	accessToken, err := token.Issue(h.cfg.Token.AccessTokenSecret, claims.UserID, token.ScopeFull)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	refreshToken, err := token.Issue(h.cfg.Token.RefreshTokenSecret, claims.UserID, token.ScopeFull)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// checkTwoFactorCode looks up userID's stored 2FA secret, confirmed or still
+// pending, decrypts it, and
+// validates code against it, writing the appropriate error response and
+// returning false on any failure.
+func (h *Handlers) checkTwoFactorCode(w http.ResponseWriter, ctx context.Context, userID, code string) bool {
+	twoFactor, err := h.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return false
+	}
+
+	if twoFactor == nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return false
+	}
+
+	secret, err := twofactor.Decrypt(twoFactor.EncryptedSecret, []byte(h.cfg.Security.TwoFactorEncryptionKey))
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return false
+	}
+
+	if !twofactor.Validate(code, secret) {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return false
+	}
+
+	return true
+}
+
+// createPersonalAccessTokenRequest is the body CreatePersonalAccessToken
+// accepts.
+type createPersonalAccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatePersonalAccessToken mints a new personal access token for the
+// authenticated user with the requested name and scopes. The plaintext
+// token is only ever returned in this response.
+//
+// @Summary Mint a new personal access token
+// @Tags personal-access-tokens
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body createPersonalAccessTokenRequest true "name, scopes, and optional expiry"
+// @Success 201 {object} models.PersonalAccessTokenCreated
+// @Failure 400
+// @Failure 500
+// @Router /api/user/tokens [post]
+func (h *Handlers) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	var req createPersonalAccessTokenRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !pat.Valid(pat.Scope(scope)) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	plaintext, hash, err := pat.Generate()
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	record := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hash,
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	id, err := h.patStore.Create(r.Context(), record)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	record.ID = id
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(models.PersonalAccessTokenCreated{
+		PersonalAccessToken: record,
+		Token:               plaintext,
+	})
+}
+
+// ListPersonalAccessTokens returns the authenticated user's personal access
+// tokens, without their hashes or the plaintext token values.
+//
+// @Summary List the authenticated user's personal access tokens
+// @Tags personal-access-tokens
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PersonalAccessToken
+// @Failure 500
+// @Router /api/user/tokens [get]
+func (h *Handlers) ListPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+
+	tokens, err := h.patStore.List(r.Context(), userID)
+	if err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokePersonalAccessToken deletes the personal access token identified by
+// the {id} URL parameter, if it belongs to the authenticated user.
+//
+// @Summary Revoke a personal access token
+// @Tags personal-access-tokens
+// @Security BearerAuth
+// @Param id path string true "token ID"
+// @Success 200
+// @Failure 500
+// @Router /api/user/tokens/{id} [delete]
+func (h *Handlers) RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDCtx).(string)
+	tokenID := chi.URLParam(r, "id")
+
+	if err := h.patStore.Revoke(r.Context(), userID, tokenID); err != nil {
+		h.logger.Error().Msg(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -2,25 +2,71 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang/mock/gomock"
+	"github.com/pquerna/otp/totp"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
 	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/password"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/token"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/twofactor"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/ws"
 )
 
+// createUserParamsMatcher matches a CreateUser argument whose Password is
+// the bcrypt hash of plainPassword and whose other fields equal user's.
+// Bcrypt salts every hash uniquely, so a plain gomock.Eq(user) can never
+// match a freshly hashed password across calls.
+type createUserParamsMatcher struct {
+	user          models.User
+	plainPassword string
+}
+
+// EqCreateUserParams builds a gomock.Matcher for a CreateUser call expected
+// to persist user with plainPassword hashed into user.Password.
+func EqCreateUserParams(user models.User, plainPassword string) gomock.Matcher {
+	return createUserParamsMatcher{user: user, plainPassword: plainPassword}
+}
+
+func (m createUserParamsMatcher) Matches(x interface{}) bool {
+	got, ok := x.(models.User)
+	if !ok {
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(got.Password), []byte(m.plainPassword)) != nil {
+		return false
+	}
+
+	want := m.user
+	want.Password = ""
+	got.Password = ""
+
+	return reflect.DeepEqual(want, got)
+}
+
+func (m createUserParamsMatcher) String() string {
+	return fmt.Sprintf("is a user matching %+v with password hashing %q", m.user, m.plainPassword)
+}
+
 func newRouter(h *Handlers, cfg *config.Config) *chi.Mux {
 	router := chi.NewRouter()
 
@@ -34,6 +80,10 @@ func newRouter(h *Handlers, cfg *config.Config) *chi.Mux {
 		r.Get("/api/user/balance", h.GetBalance)
 		r.Post("/api/user/balance/withdraw", h.CreateWithdraw)
 		r.Get("/api/user/balance/withdrawals", h.GetWithdrawals)
+		r.Post("/api/user/2fa/enroll", h.EnrollTwoFactor)
+		r.Post("/api/user/2fa/verify", h.VerifyTwoFactor)
+		r.Post("/api/user/2fa/disable", h.DisableTwoFactor)
+		r.Post("/api/user/2fa/challenge", h.ChallengeTwoFactor)
 	})
 
 	return router
@@ -134,12 +184,12 @@ func TestHandlers_Register(t *testing.T) {
 			repoMock := NewMockRepository(ctrl)
 			jobStoreMock := NewMockJobStore(ctrl)
 
-			h := New(repoMock, jobStoreMock, &logger, cfg)
+			h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
 
 			router.Post(tt.query, h.Register)
 
 			if !tt.withoutBody {
-				repoMock.EXPECT().CreateUser(gomock.Any(), tt.mockUser).Return(&tt.mockUser, tt.mockError)
+				repoMock.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(tt.mockUser, tt.mockUser.Password)).Return(&tt.mockUser, tt.mockError)
 			}
 
 			router.ServeHTTP(w, r)
@@ -150,6 +200,55 @@ func TestHandlers_Register(t *testing.T) {
 	}
 }
 
+func TestEqCreateUserParams(t *testing.T) {
+	hash, err := password.Hash(bcrypt.MinCost, "12345")
+	assert.NoError(t, err)
+
+	want := models.User{Login: "login"}
+
+	tests := []struct {
+		name    string
+		arg     interface{}
+		matches bool
+	}{
+		{
+			name:    "matches a user hashed from the expected password",
+			arg:     models.User{Login: "login", Password: hash},
+			matches: true,
+		},
+		{
+			name:    "rejects a user hashed from the wrong password",
+			arg:     models.User{Login: "login", Password: mustHash(t, "wrong-password")},
+			matches: false,
+		},
+		{
+			name:    "rejects a user whose other fields differ",
+			arg:     models.User{Login: "someone-else", Password: hash},
+			matches: false,
+		},
+		{
+			name:    "rejects a non-User argument",
+			arg:     "login",
+			matches: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, EqCreateUserParams(want, "12345").Matches(tt.arg))
+		})
+	}
+}
+
+func mustHash(t *testing.T, plaintext string) string {
+	t.Helper()
+
+	hash, err := password.Hash(bcrypt.MinCost, plaintext)
+	assert.NoError(t, err)
+
+	return hash
+}
+
 func TestHandlers_Login(t *testing.T) {
 	type want struct {
 		code        int
@@ -245,12 +344,16 @@ func TestHandlers_Login(t *testing.T) {
 			repoMock := NewMockRepository(ctrl)
 			jobStoreMock := NewMockJobStore(ctrl)
 
-			h := New(repoMock, jobStoreMock, &logger, cfg)
+			h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
 
 			router.Post(tt.query, h.Login)
 
 			if !tt.withoutBody {
 				repoMock.EXPECT().CheckPassword(gomock.Any(), tt.mockUser).Return(&tt.mockUser, tt.mockError)
+
+				if tt.mockError == nil {
+					repoMock.EXPECT().GetTwoFactor(gomock.Any(), tt.mockUser.ID).Return(nil, nil)
+				}
 			}
 
 			router.ServeHTTP(w, r)
@@ -260,6 +363,82 @@ func TestHandlers_Login(t *testing.T) {
 	}
 }
 
+// TestHandlers_Login_TwoFactor exercises the two-step login flow: Login
+// issues a partial token for a 2FA-enabled user instead of a full pair, and
+// that partial token only becomes a full pair after ChallengeTwoFactor
+// verifies a TOTP code.
+func TestHandlers_Login_TwoFactor(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	cfg := config.New()
+
+	enrollment, err := twofactor.Generate("userID")
+	assert.NoError(t, err)
+
+	encryptedSecret, err := twofactor.Encrypt(enrollment.Secret, []byte(cfg.Security.TwoFactorEncryptionKey))
+	assert.NoError(t, err)
+
+	mockUser := models.User{ID: "userID", Login: "login", Password: "12345"}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := NewMockRepository(ctrl)
+	jobStoreMock := NewMockJobStore(ctrl)
+
+	h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
+
+	router := chi.NewRouter()
+	router.Post("/api/user/login", h.Login)
+	router.Post("/api/user/2fa/challenge", h.ChallengeTwoFactor)
+
+	repoMock.EXPECT().CheckPassword(gomock.Any(), models.User{Login: "login", Password: "12345"}).Return(&mockUser, nil)
+	repoMock.EXPECT().GetTwoFactor(gomock.Any(), "userID").Return(&models.TwoFactor{
+		UserID:          "userID",
+		EncryptedSecret: encryptedSecret,
+		Enabled:         true,
+	}, nil)
+
+	loginBody, _ := json.Marshal(map[string]string{"login": "login", "password": "12345"})
+	loginReq, _ := http.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(string(loginBody)))
+	loginW := httptest.NewRecorder()
+
+	router.ServeHTTP(loginW, loginReq)
+
+	assert.Equal(t, http.StatusOK, loginW.Code)
+
+	var partialPair models.TokenPair
+	assert.NoError(t, json.NewDecoder(loginW.Body).Decode(&partialPair))
+	assert.NotEmpty(t, partialPair.AccessToken)
+	assert.Empty(t, partialPair.RefreshToken)
+
+	claims, err := token.Parse(cfg.Token.AccessTokenSecret, partialPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ScopeTwoFactorPending, claims.Scope)
+
+	validCode, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	repoMock.EXPECT().GetTwoFactor(gomock.Any(), "userID").Return(&models.TwoFactor{
+		UserID:          "userID",
+		EncryptedSecret: encryptedSecret,
+		Enabled:         true,
+	}, nil)
+
+	challengeBody, _ := json.Marshal(map[string]string{"code": validCode})
+	challengeReq, _ := http.NewRequest(http.MethodPost, "/api/user/2fa/challenge", strings.NewReader(string(challengeBody)))
+	challengeReq.Header.Set("Authorization", "Bearer "+partialPair.AccessToken)
+	challengeW := httptest.NewRecorder()
+
+	router.ServeHTTP(challengeW, challengeReq)
+
+	assert.Equal(t, http.StatusOK, challengeW.Code)
+
+	var fullPair models.TokenPair
+	assert.NoError(t, json.NewDecoder(challengeW.Body).Decode(&fullPair))
+	assert.NotEmpty(t, fullPair.AccessToken)
+	assert.NotEmpty(t, fullPair.RefreshToken)
+}
+
 func TestHandlers_CreateOrder(t *testing.T) {
 	type want struct {
 		code        int
@@ -349,7 +528,7 @@ func TestHandlers_CreateOrder(t *testing.T) {
 			repoMock := NewMockRepository(ctrl)
 			jobStoreMock := NewMockJobStore(ctrl)
 
-			h := New(repoMock, jobStoreMock, &logger, cfg)
+			h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
 
 			router.Post(tt.query, h.CreateOrder)
 
@@ -427,7 +606,7 @@ func TestHandlers_GetOrders(t *testing.T) {
 			repoMock := NewMockRepository(ctrl)
 			jobStoreMock := NewMockJobStore(ctrl)
 
-			h := New(repoMock, jobStoreMock, &logger, cfg)
+			h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
 
 			router.Get(tt.query, h.GetOrders)
 
@@ -446,4 +625,305 @@ func TestHandlers_GetOrders(t *testing.T) {
 			assert.Equal(t, tt.want.response, string(body), "invalid response body")
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestHandlers_EnrollTwoFactor(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "/api/user/2fa/enroll", nil)
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	cfg := config.New()
+
+	repoMock := NewMockRepository(ctrl)
+	jobStoreMock := NewMockJobStore(ctrl)
+
+	h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
+
+	router.Post("/api/user/2fa/enroll", h.EnrollTwoFactor)
+
+	repoMock.EXPECT().SaveTwoFactorSecret(gomock.Any(), "userID", gomock.Any(), gomock.Any()).Return(nil)
+
+	router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtx, "userID")))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var enrollment models.TwoFactorEnrollment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&enrollment))
+	assert.NotEmpty(t, enrollment.OTPAuthURL)
+	assert.NotEmpty(t, enrollment.QRCodePNG)
+	assert.Len(t, enrollment.RecoveryCodes, 8)
+}
+
+func TestHandlers_VerifyTwoFactor(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	cfg := config.New()
+
+	enrollment, err := twofactor.Generate("userID")
+	assert.NoError(t, err)
+
+	encryptedSecret, err := twofactor.Encrypt(enrollment.Secret, []byte(cfg.Security.TwoFactorEncryptionKey))
+	assert.NoError(t, err)
+
+	validCode, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantCode int
+	}{
+		{name: "valid code activates 2fa", code: validCode, wantCode: http.StatusOK},
+		{name: "invalid code is rejected", code: "000000", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"code": tt.code})
+			r, _ := http.NewRequest(http.MethodPost, "/api/user/2fa/verify", strings.NewReader(string(body)))
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := NewMockRepository(ctrl)
+			jobStoreMock := NewMockJobStore(ctrl)
+
+			h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
+
+			router.Post("/api/user/2fa/verify", h.VerifyTwoFactor)
+
+			repoMock.EXPECT().GetTwoFactor(gomock.Any(), "userID").Return(&models.TwoFactor{
+				UserID:          "userID",
+				EncryptedSecret: encryptedSecret,
+			}, nil)
+
+			if tt.wantCode == http.StatusOK {
+				repoMock.EXPECT().ActivateTwoFactor(gomock.Any(), "userID").Return(nil)
+			}
+
+			router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtx, "userID")))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
+
+func TestHandlers_ChallengeTwoFactor(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	cfg := config.New()
+
+	enrollment, err := twofactor.Generate("userID")
+	assert.NoError(t, err)
+
+	encryptedSecret, err := twofactor.Encrypt(enrollment.Secret, []byte(cfg.Security.TwoFactorEncryptionKey))
+	assert.NoError(t, err)
+
+	validCode, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	partialToken, err := token.Issue(cfg.Token.AccessTokenSecret, "userID", token.ScopeTwoFactorPending)
+	assert.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]string{"code": validCode})
+	r, _ := http.NewRequest(http.MethodPost, "/api/user/2fa/challenge", strings.NewReader(string(body)))
+	r.Header.Set("Authorization", "Bearer "+partialToken)
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := NewMockRepository(ctrl)
+	jobStoreMock := NewMockJobStore(ctrl)
+
+	h := New(repoMock, jobStoreMock, &logger, cfg, ws.NewHub(), nil)
+
+	router.Post("/api/user/2fa/challenge", h.ChallengeTwoFactor)
+
+	repoMock.EXPECT().GetTwoFactor(gomock.Any(), "userID").Return(&models.TwoFactor{
+		UserID:          "userID",
+		EncryptedSecret: encryptedSecret,
+		Enabled:         true,
+	}, nil)
+
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var pair models.TokenPair
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&pair))
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.NotEmpty(t, pair.RefreshToken)
+}
+
+func TestHandlers_CreatePersonalAccessToken(t *testing.T) {
+	type want struct {
+		code int
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		mockError error
+		want      want
+	}{
+		{
+			name: "successfully created",
+			body: `{"name": "ci", "scopes": ["orders:read"]}`,
+			want: want{code: http.StatusCreated},
+		},
+		{
+			name: "invalid request body",
+			body: `not json`,
+			want: want{code: http.StatusBadRequest},
+		},
+		{
+			name: "unknown scope",
+			body: `{"name": "ci", "scopes": ["not:a:scope"]}`,
+			want: want{code: http.StatusBadRequest},
+		},
+		{
+			name:      "store error",
+			body:      `{"name": "ci", "scopes": ["orders:read"]}`,
+			mockError: errors.New("db is down"),
+			want:      want{code: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodPost, "/api/user/tokens", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+			cfg := config.New()
+
+			patStoreMock := NewMockPersonalAccessTokenStore(ctrl)
+
+			h := New(nil, nil, &logger, cfg, ws.NewHub(), patStoreMock)
+
+			router.Post("/api/user/tokens", h.CreatePersonalAccessToken)
+
+			if tt.want.code != http.StatusBadRequest {
+				patStoreMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return("id", tt.mockError)
+			}
+
+			router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtx, "userID")))
+
+			assert.Equal(t, tt.want.code, w.Code)
+		})
+	}
+}
+
+func TestHandlers_ListPersonalAccessTokens(t *testing.T) {
+	type want struct {
+		code int
+	}
+
+	tests := []struct {
+		name       string
+		mockTokens []models.PersonalAccessToken
+		mockError  error
+		want       want
+	}{
+		{
+			name:       "successfully listed",
+			mockTokens: []models.PersonalAccessToken{{ID: "id", Name: "ci"}},
+			want:       want{code: http.StatusOK},
+		},
+		{
+			name:      "store error",
+			mockError: errors.New("db is down"),
+			want:      want{code: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/api/user/tokens", nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+			cfg := config.New()
+
+			patStoreMock := NewMockPersonalAccessTokenStore(ctrl)
+
+			h := New(nil, nil, &logger, cfg, ws.NewHub(), patStoreMock)
+
+			router.Get("/api/user/tokens", h.ListPersonalAccessTokens)
+
+			patStoreMock.EXPECT().List(gomock.Any(), "userID").Return(tt.mockTokens, tt.mockError)
+
+			router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtx, "userID")))
+
+			assert.Equal(t, tt.want.code, w.Code)
+		})
+	}
+}
+
+func TestHandlers_RevokePersonalAccessToken(t *testing.T) {
+	type want struct {
+		code int
+	}
+
+	tests := []struct {
+		name      string
+		mockError error
+		want      want
+	}{
+		{
+			name: "successfully revoked",
+			want: want{code: http.StatusOK},
+		},
+		{
+			name:      "store error",
+			mockError: errors.New("db is down"),
+			want:      want{code: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodDelete, "/api/user/tokens/tokenID", nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+			cfg := config.New()
+
+			patStoreMock := NewMockPersonalAccessTokenStore(ctrl)
+
+			h := New(nil, nil, &logger, cfg, ws.NewHub(), patStoreMock)
+
+			router.Delete("/api/user/tokens/{id}", h.RevokePersonalAccessToken)
+
+			patStoreMock.EXPECT().Revoke(gomock.Any(), "userID", "tokenID").Return(tt.mockError)
+
+			router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtx, "userID")))
+
+			assert.Equal(t, tt.want.code, w.Code)
+		})
+	}
+}
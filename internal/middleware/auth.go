@@ -0,0 +1,109 @@
+// Package middleware holds chi middleware shared across the router.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/handlers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/pat"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/token"
+)
+
+// PersonalAccessTokenResolver looks up a personal access token by its
+// sha256 hash and records its usage. It is satisfied by
+// postgres.PersonalAccessTokenStore.
+type PersonalAccessTokenResolver interface {
+	FindByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error)
+	TouchLastUsed(ctx context.Context, hash string) error
+}
+
+// Authenticate requires a valid "Bearer <token>" Authorization header and
+// stores the resulting user ID under handlers.UserIDCtx for downstream
+// handlers. The token may be either a JWT signed with
+// cfg.Token.AccessTokenSecret, or a personal access token (prefixed
+// pat.Prefix) resolved via patStore; in the latter case the token's granted
+// scopes are stored under handlers.PATScopesCtx for RequireScope to check.
+// JWTs scoped token.ScopeTwoFactorPending are rejected, since they only
+// grant access to the 2FA challenge endpoint.
+func Authenticate(cfg *config.Config, patStore PersonalAccessTokenResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if tokenString == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			if strings.HasPrefix(tokenString, pat.Prefix) {
+				authenticatePAT(w, r, next, patStore, tokenString)
+
+				return
+			}
+
+			claims, err := token.Parse(cfg.Token.AccessTokenSecret, tokenString)
+			if err != nil || claims.Scope == token.ScopeTwoFactorPending {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			setUserID(r, claims.UserID)
+
+			ctx := context.WithValue(r.Context(), handlers.UserIDCtx, claims.UserID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authenticatePAT(w http.ResponseWriter, r *http.Request, next http.Handler, patStore PersonalAccessTokenResolver, tokenString string) {
+	hash := pat.Hash(tokenString)
+
+	record, err := patStore.FindByHash(r.Context(), hash)
+	if err != nil || record == nil || (record.ExpiresAt != nil && record.ExpiresAt.Before(time.Now())) {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	go func() {
+		_ = patStore.TouchLastUsed(context.Background(), hash)
+	}()
+
+	scopes := make([]pat.Scope, len(record.Scopes))
+	for i, scope := range record.Scopes {
+		scopes[i] = pat.Scope(scope)
+	}
+
+	setUserID(r, record.UserID)
+
+	ctx := context.WithValue(r.Context(), handlers.UserIDCtx, record.UserID)
+	ctx = context.WithValue(ctx, handlers.PATScopesCtx, scopes)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope restricts access to requests whose personal access token
+// grants scope. Requests authenticated with a full JWT carry no scopes in
+// context and are never restricted, since a logged-in user can always do
+// anything their own account can.
+func RequireScope(scope pat.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := r.Context().Value(handlers.PATScopesCtx).([]pat.Scope)
+			if ok && !pat.HasScope(scopes, scope) {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
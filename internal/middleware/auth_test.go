@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/handlers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/models"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/pat"
+)
+
+// fakePersonalAccessTokenResolver is a hand-rolled stub for
+// PersonalAccessTokenResolver; the interface is small enough not to warrant
+// a generated mock.
+type fakePersonalAccessTokenResolver struct {
+	record *models.PersonalAccessToken
+	err    error
+}
+
+func (f *fakePersonalAccessTokenResolver) FindByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	return f.record, f.err
+}
+
+func (f *fakePersonalAccessTokenResolver) TouchLastUsed(ctx context.Context, hash string) error {
+	return nil
+}
+
+func TestAuthenticate_PersonalAccessToken(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		resolver PersonalAccessTokenResolver
+		wantCode int
+	}{
+		{
+			name: "valid token",
+			resolver: &fakePersonalAccessTokenResolver{record: &models.PersonalAccessToken{
+				UserID: "userID",
+				Scopes: []string{string(pat.ScopeOrdersRead)},
+			}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "expired token",
+			resolver: &fakePersonalAccessTokenResolver{record: &models.PersonalAccessToken{
+				UserID:    "userID",
+				ExpiresAt: &expired,
+			}},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "unknown hash",
+			resolver: &fakePersonalAccessTokenResolver{record: nil},
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.New()
+
+			router := chi.NewRouter()
+			router.With(Authenticate(cfg, tt.resolver)).Get("/api/user/orders", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r, _ := http.NewRequest(http.MethodGet, "/api/user/orders", nil)
+			r.Header.Set("Authorization", "Bearer "+pat.Prefix+"sometoken")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []pat.Scope
+		required pat.Scope
+		wantCode int
+	}{
+		{
+			name:     "granted scope allows the request",
+			scopes:   []pat.Scope{pat.ScopeOrdersRead},
+			required: pat.ScopeOrdersRead,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "missing scope is rejected",
+			scopes:   []pat.Scope{pat.ScopeOrdersRead},
+			required: pat.ScopeOrdersWrite,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "no scopes in context (full JWT) is never restricted",
+			scopes:   nil,
+			required: pat.ScopeOrdersWrite,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.With(RequireScope(tt.required)).Get("/api/user/orders", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r, _ := http.NewRequest(http.MethodGet, "/api/user/orders", nil)
+			ctx := r.Context()
+
+			if tt.scopes != nil {
+				ctx = context.WithValue(ctx, handlers.PATScopesCtx, tt.scopes)
+			}
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r.WithContext(ctx))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
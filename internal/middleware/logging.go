@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+type loggingCtxKey string
+
+// userIDRefCtxKey holds a *string that Authenticate fills in once it
+// resolves the caller's user ID. RequestLogger wraps the whole router,
+// outside the Authenticate middleware that only guards authenticated
+// routes, so by the time next.ServeHTTP returns to RequestLogger the
+// *http.Request it holds still carries the pre-Authenticate context:
+// context.WithValue further down the chain derives a new request rather
+// than mutating this one. Sharing a pointer instead of a context key lets
+// Authenticate's write show up here regardless of how deep it is nested.
+const userIDRefCtxKey loggingCtxKey = "requestUserIDRef"
+
+// setUserID records userID against the request for RequestLogger to pick
+// up, if the request passed through RequestLogger. It is a no-op otherwise.
+func setUserID(r *http.Request, userID string) {
+	if ref, ok := r.Context().Value(userIDRefCtxKey).(*string); ok {
+		*ref = userID
+	}
+}
+
+// RequestLogger returns a chi middleware that emits one structured JSON log
+// line per request to logger, recording method, path, status, duration,
+// response size, the chi request ID, and (once Authenticate has run)
+// user_id. It replaces chi's plaintext middleware.Logger.
+func RequestLogger(logger *zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			var userID string
+			r = r.WithContext(context.WithValue(r.Context(), userIDRefCtxKey, &userID))
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			event := logger.Info()
+
+			switch {
+			case ww.Status() >= http.StatusInternalServerError:
+				event = logger.Error()
+			case ww.Status() >= http.StatusBadRequest:
+				event = logger.Warn()
+			}
+
+			event = event.
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", ww.Status()).
+				Dur("duration", time.Since(start)).
+				Int("bytes", ww.BytesWritten()).
+				Str("request_id", chimiddleware.GetReqID(r.Context()))
+
+			if userID != "" {
+				event = event.Str("user_id", userID)
+			}
+
+			event.Msg("request")
+		})
+	}
+}
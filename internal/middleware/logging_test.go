@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/token"
+)
+
+// TestRequestLogger wires RequestLogger outside Authenticate, exactly as
+// router.New does, so it also guards against regressing the ordering bug
+// where Authenticate's context enrichment failed to reach RequestLogger's
+// request because context.WithValue derives a request rather than mutating
+// the one passed down the chain.
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	cfg := config.New()
+	accessToken, err := token.Issue(cfg.Token.AccessTokenSecret, "userID", token.ScopeFull)
+	assert.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(chimiddleware.RequestID)
+	router.Use(RequestLogger(&logger))
+	router.With(Authenticate(cfg, nil)).Get("/api/user/balance", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/api/user/balance", nil)
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, http.MethodGet, entry["method"])
+	assert.Equal(t, "/api/user/balance", entry["path"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, "userID", entry["user_id"])
+	assert.NotEmpty(t, entry["request_id"])
+	assert.Contains(t, entry, "duration")
+	assert.Contains(t, entry, "bytes")
+}
+
+func TestRequestLogger_NoUserID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	router := chi.NewRouter()
+	router.Use(chimiddleware.RequestID)
+	router.Use(RequestLogger(&logger))
+	router.Post("/api/user/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r, _ := http.NewRequest(http.MethodPost, "/api/user/login", bytes.NewReader([]byte(`{"login":"login","password":"hunter2"}`)))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	logLine := buf.String()
+
+	assert.NotContains(t, logLine, "hunter2")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.NotContains(t, entry, "user_id")
+}
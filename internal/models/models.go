@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+// User represents a registered loyalty system user. Password carries the
+// plaintext password submitted by the client on Register and Login; once
+// persisted it is a bcrypt hash (see internal/password), never plaintext.
+type User struct {
+	ID       string `json:"-"`
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Order represents an order submitted by a user for accrual processing.
+type Order struct {
+	UserID     string    `json:"-"`
+	Number     string    `json:"number"`
+	Status     string    `json:"status"`
+	Accrual    float64   `json:"accrual,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// ResponseOrder is the representation of an order returned from GetOrders.
+type ResponseOrder struct {
+	Number     string    `json:"number"`
+	Status     string    `json:"status"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// ResponseOrderWithAccrual adds the accrual amount to ResponseOrder once it is known.
+type ResponseOrderWithAccrual struct {
+	ResponseOrder
+	Accrual float64 `json:"accrual,omitempty"`
+}
+
+// Balance is the current and withdrawn accrual balance for a user.
+type Balance struct {
+	Current   float64 `json:"current"`
+	Withdrawn float64 `json:"withdrawn"`
+}
+
+// Withdraw represents a withdrawal request against the accrual balance.
+type Withdraw struct {
+	UserID      string    `json:"-"`
+	Order       string    `json:"order"`
+	Sum         float64   `json:"sum"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// TwoFactor is a user's TOTP enrollment: the encrypted secret and unused
+// recovery codes, plus whether enrollment has been confirmed.
+type TwoFactor struct {
+	UserID          string   `json:"-"`
+	EncryptedSecret string   `json:"-"`
+	RecoveryCodes   []string `json:"-"`
+	Enabled         bool     `json:"enabled"`
+}
+
+// TwoFactorEnrollment is returned by Handlers.EnrollTwoFactor so the client
+// can render the QR code and show the recovery codes exactly once.
+type TwoFactorEnrollment struct {
+	OTPAuthURL    string   `json:"otpauth_url"`
+	QRCodePNG     []byte   `json:"qr_code_png"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TokenPair is the access/refresh token pair issued at login and at the end
+// of a successful 2FA challenge.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// PersonalAccessToken lets a script call the loyalty API without a JWT. The
+// plaintext token is only ever returned once, at creation; everything else
+// looks it up and authenticates by its sha256 hash.
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// PersonalAccessTokenCreated is returned by Handlers.CreatePersonalAccessToken.
+// Token is the plaintext token; it is never shown again after this response.
+type PersonalAccessTokenCreated struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// AccrualRegistration is the producer-registration record the gophermart
+// service hands to the accrual system so it knows where to call back with
+// order status updates. It is re-sent on every startup, so the accrual
+// system (and our own storage of it) must treat registration as an upsert.
+type AccrualRegistration struct {
+	// SupervisionCallbackURL is polled/called by the accrual system to check
+	// that this producer is still alive.
+	SupervisionCallbackURL string `json:"supervision_callback_url"`
+	// AccrualCallbackURL receives order status push notifications.
+	AccrualCallbackURL string `json:"accrual_callback_url"`
+	// Statuses lists the order statuses this producer wants to be notified about.
+	Statuses  []string  `json:"statuses"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AccrualStatusUpdate is the payload the accrual system pushes to
+// AccrualCallbackURL whenever an order's status changes.
+type AccrualStatusUpdate struct {
+	Order   string  `json:"order"`
+	Status  string  `json:"status"`
+	Accrual float64 `json:"accrual,omitempty"`
+}
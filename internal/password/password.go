@@ -0,0 +1,33 @@
+// Package password hashes and verifies user passwords with bcrypt.
+package password
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash bcrypt-hashes plaintext at the given work factor. cost is normally
+// cfg.Security.BcryptCost; passing 0 makes bcrypt fall back to
+// bcrypt.DefaultCost.
+func Hash(cost int, plaintext string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintext), cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Verify reports whether plaintext matches hash.
+func Verify(hash, plaintext string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintext)) == nil
+}
+
+// NeedsRehash reports whether stored is not a bcrypt hash, so it must have
+// been written before password hashing was introduced. Login should treat a
+// true result as a sign to re-save the user's password through Hash once
+// the plaintext has been verified against the legacy value.
+func NeedsRehash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+
+	return err != nil
+}
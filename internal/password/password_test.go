@@ -0,0 +1,25 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := Hash(bcrypt.MinCost, "correct horse battery staple")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "correct horse battery staple", hash)
+
+	assert.True(t, Verify(hash, "correct horse battery staple"))
+	assert.False(t, Verify(hash, "wrong password"))
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := Hash(bcrypt.MinCost, "12345")
+	assert.NoError(t, err)
+
+	assert.False(t, NeedsRehash(hash))
+	assert.True(t, NeedsRehash("12345"))
+}
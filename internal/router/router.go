@@ -0,0 +1,59 @@
+// Package router wires the chi router used by the gophermart HTTP server.
+package router
+
+import (
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/docs"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/handlers"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/middleware"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/pat"
+)
+
+// New builds the chi.Mux serving every gophermart endpoint. patStore
+// resolves personal access tokens for requests authenticated with one
+// instead of a JWT; logger receives one structured request log line per
+// request.
+func New(h *handlers.Handlers, cfg *config.Config, patStore middleware.PersonalAccessTokenResolver, logger *zerolog.Logger) *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(chimiddleware.RequestID)
+	r.Use(middleware.RequestLogger(logger))
+
+	r.Get("/openapi.json", docs.SpecHandler)
+	r.Handle("/swagger/*", docs.UIHandler("/swagger/"))
+
+	r.Post("/api/user/register", h.Register)
+	r.Post("/api/user/login", h.Login)
+
+	r.Post("/api/internal/accrual/callback", h.AccrualCallback)
+
+	// 2fa/challenge authenticates itself against a partial-auth token, so it
+	// must stay outside the Authenticate group below, which rejects those.
+	r.Post("/api/user/2fa/challenge", h.ChallengeTwoFactor)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(cfg, patStore))
+
+		r.With(middleware.RequireScope(pat.ScopeOrdersWrite)).Post("/api/user/orders", h.CreateOrder)
+		r.With(middleware.RequireScope(pat.ScopeOrdersRead)).Get("/api/user/orders", h.GetOrders)
+		r.With(middleware.RequireScope(pat.ScopeBalanceRead)).Get("/api/user/balance", h.GetBalance)
+		r.With(middleware.RequireScope(pat.ScopeBalanceWithdraw)).Post("/api/user/balance/withdraw", h.CreateWithdraw)
+		r.With(middleware.RequireScope(pat.ScopeBalanceRead)).Get("/api/user/balance/withdrawals", h.GetWithdrawals)
+
+		r.Get("/api/user/events", h.Subscribe)
+
+		r.Post("/api/user/2fa/enroll", h.EnrollTwoFactor)
+		r.Post("/api/user/2fa/verify", h.VerifyTwoFactor)
+		r.Post("/api/user/2fa/disable", h.DisableTwoFactor)
+
+		r.Post("/api/user/tokens", h.CreatePersonalAccessToken)
+		r.Get("/api/user/tokens", h.ListPersonalAccessTokens)
+		r.Delete("/api/user/tokens/{id}", h.RevokePersonalAccessToken)
+	})
+
+	return r
+}
@@ -0,0 +1,45 @@
+// Package server wraps net/http.Server with the context-driven shutdown
+// convention the rest of the service uses.
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// Server is a thin wrapper around http.Server that shuts itself down when
+// ctx is cancelled.
+type Server struct {
+	ctx        context.Context
+	httpServer *http.Server
+}
+
+// New builds a Server that will serve handler on addr until ctx is done.
+func New(ctx context.Context, handler http.Handler, addr string) *Server {
+	s := &Server{
+		ctx: ctx,
+		httpServer: &http.Server{
+			Addr:    addr,
+			Handler: handler,
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = s.httpServer.Close()
+	}()
+
+	return s
+}
+
+// Start runs the HTTP server. It blocks until the server stops, returning
+// nil if that happened because ctx was cancelled.
+func (s *Server) Start() error {
+	err := s.httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
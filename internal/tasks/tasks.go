@@ -0,0 +1,144 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/accrual"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+)
+
+// TaskInterface is a unit of recurring background work executed by the
+// worker pool.
+type TaskInterface interface {
+	Run(ctx context.Context) error
+}
+
+// ChangeOrderStatusFunc persists a new status (and accrual, when present) for
+// an order and returns the ID of the user it belongs to. It is satisfied by
+// postgres.Repository.ChangeOrderStatus.
+type ChangeOrderStatusFunc func(ctx context.Context, number string, status string, accrual float64) (string, error)
+
+// SilentOrdersFunc returns the numbers of orders that have not received a
+// status update for at least deadline. It is used by hybrid mode to fall
+// back to polling only for orders the accrual system has gone quiet on.
+type SilentOrdersFunc func(ctx context.Context, deadline time.Duration) ([]string, error)
+
+// AccrualChecker queries the accrual system for a single order's status. It
+// is satisfied by *accrual.Client.
+type AccrualChecker interface {
+	CheckOrder(ctx context.Context, number string) (*accrual.Order, error)
+}
+
+// CheckOrderStatusTask polls the accrual system for orders still in
+// progress and forwards the result to ChangeOrderStatus. Its behaviour is
+// governed by cfg.AccrualMode: in "push" mode it is a no-op, since updates
+// arrive via Handlers.AccrualCallback instead; in "poll" mode it checks
+// every outstanding order; in "hybrid" mode it only checks orders that have
+// gone silent past AccrualStatusDeadline. In both poll and hybrid mode it
+// also implements workers.JobRunner, so the worker pool can check a freshly
+// submitted order right away instead of waiting for the next sweep.
+type CheckOrderStatusTask struct {
+	accrualClient     AccrualChecker
+	mode              config.AccrualMode
+	silentDeadline    time.Duration
+	logger            *zerolog.Logger
+	changeOrderStatus ChangeOrderStatusFunc
+	silentOrders      SilentOrdersFunc
+}
+
+// NewCheckOrderStatusTask builds a task bound to accrualClient whose polling
+// behaviour is governed by mode and silentDeadline.
+func NewCheckOrderStatusTask(
+	accrualClient AccrualChecker,
+	mode config.AccrualMode,
+	silentDeadline time.Duration,
+	logger *zerolog.Logger,
+	changeOrderStatus ChangeOrderStatusFunc,
+	silentOrders SilentOrdersFunc,
+) *CheckOrderStatusTask {
+	return &CheckOrderStatusTask{
+		accrualClient:     accrualClient,
+		mode:              mode,
+		silentDeadline:    silentDeadline,
+		logger:            logger,
+		changeOrderStatus: changeOrderStatus,
+		silentOrders:      silentOrders,
+	}
+}
+
+// Run executes a single sweep over orders still awaiting a final status. The
+// worker pool is responsible for scheduling repeated calls.
+func (t *CheckOrderStatusTask) Run(ctx context.Context) error {
+	// Push mode relies entirely on Handlers.AccrualCallback; there is
+	// nothing for the polling task to do.
+	if t.mode == config.AccrualModePush {
+		return nil
+	}
+
+	deadline := time.Duration(0)
+	if t.mode == config.AccrualModeHybrid {
+		deadline = t.silentDeadline
+	}
+
+	orders, err := t.silentOrders(ctx, deadline)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range orders {
+		if err := t.checkOrder(ctx, number); err != nil {
+			t.logger.Error().Msgf("polling accrual system for order %s: %s", number, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// RunJob checks a single order's status right away, independent of Run's
+// scheduled sweep. The worker pool calls it for jobs the JobStore hands out
+// as orders are created, so a freshly submitted order gets its first
+// accrual check without waiting on the sweep interval.
+func (t *CheckOrderStatusTask) RunJob(ctx context.Context, orderNumber string) error {
+	if t.mode == config.AccrualModePush {
+		return nil
+	}
+
+	return t.checkOrder(ctx, orderNumber)
+}
+
+// checkOrder asks the accrual system for number's status and persists it via
+// changeOrderStatus, doing nothing if the accrual system hasn't registered
+// the order yet.
+func (t *CheckOrderStatusTask) checkOrder(ctx context.Context, number string) error {
+	order, err := t.accrualClient.CheckOrder(ctx, number)
+	if err != nil {
+		return err
+	}
+
+	if order == nil {
+		return nil
+	}
+
+	_, err = t.changeOrderStatus(ctx, number, order.GophermartStatus(), order.Accrual)
+
+	return err
+}
+
+// TaskStore is the set of background tasks the worker pool runs on each tick
+// and hands every queued job to.
+type TaskStore struct {
+	tasks []TaskInterface
+}
+
+// NewTaskStore wraps the given tasks for use by the worker pool.
+func NewTaskStore(tasks []TaskInterface) *TaskStore {
+	return &TaskStore{tasks: tasks}
+}
+
+// Tasks returns every task registered with the store.
+func (s *TaskStore) Tasks() []TaskInterface {
+	return s.tasks
+}
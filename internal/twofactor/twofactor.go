@@ -0,0 +1,151 @@
+// Package twofactor implements TOTP-based two-factor authentication:
+// secret provisioning, code verification, recovery codes, and the
+// at-rest encryption applied before a secret is handed to a repository
+// for storage.
+package twofactor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/skip2/go-qrcode"
+)
+
+// qrSize is the side length, in pixels, of the enrollment QR code.
+const qrSize = 256
+
+// ErrInvalidCode describes a code that does not match the current (or
+// adjacent, within the allowed skew) TOTP value for secret. Validate reports
+// such a code by returning false; it does not return this error.
+var ErrInvalidCode = errors.New("invalid or expired code")
+
+const (
+	issuer            = "gophermart"
+	recoveryCodeCount = 8
+	recoveryCodeBytes = 5
+)
+
+// Enrollment is the provisioning material handed back to a user enrolling
+// in 2FA: the secret to encrypt and store, the otpauth:// URI to render as
+// a QR code, and the one-time recovery codes to show exactly once.
+type Enrollment struct {
+	Secret        string
+	OTPAuthURL    string
+	RecoveryCodes []string
+}
+
+// Generate provisions a new TOTP secret and recovery codes for accountLogin.
+// The secret is returned in cleartext; callers must encrypt it with Encrypt
+// before persisting it.
+func Generate(accountLogin string) (*Enrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountLogin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate totp key: %w", err)
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("generate recovery codes: %w", err)
+	}
+
+	return &Enrollment{
+		Secret:        key.Secret(),
+		OTPAuthURL:    key.URL(),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// Validate reports whether code is a valid current TOTP value for secret.
+func Validate(code, secret string) bool {
+	return totp.Validate(code, secret)
+}
+
+// QRCodePNG renders otpauthURL as a PNG QR code for the user to scan into
+// their authenticator app.
+func QRCodePNG(otpauthURL string) ([]byte, error) {
+	png, err := qrcode.Encode(otpauthURL, qrcode.Medium, qrSize)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+
+	return png, nil
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return nil, err
+		}
+
+		codes[i] = hex.EncodeToString(buf)
+	}
+
+	return codes, nil
+}
+
+// Encrypt seals secret with AES-GCM under key, returning a base32-encoded
+// ciphertext suitable for storing in a TEXT column.
+func Encrypt(secret string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+
+	return base32.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(ciphertext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base32.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decode ciphertext: %w", err)
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("ciphertext too short")
+	}
+
+	nonce, sealedSecret := sealed[:nonceSize], sealed[nonceSize:]
+
+	plain, err := gcm.Open(nil, nonce, sealedSecret, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret: %w", err)
+	}
+
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("build cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
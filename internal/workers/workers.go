@@ -0,0 +1,90 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/config"
+	"github.com/KokoulinM/go-musthave-diploma-tpl/internal/tasks"
+)
+
+// JobStore is implemented by anything that can hand out and store pending
+// background jobs, e.g. postgres.JobStore.
+type JobStore interface {
+	AddJob(ctx context.Context, orderNumber string) error
+	NextJobs(ctx context.Context, limit int) ([]string, error)
+}
+
+// JobRunner is implemented by a task that can act on a single queued job
+// right away, instead of waiting for its next scheduled Run. Tasks that
+// don't implement it are only ever driven by their own Run schedule.
+type JobRunner interface {
+	RunJob(ctx context.Context, orderNumber string) error
+}
+
+// WorkerPool periodically drains the job store and runs each registered
+// task against it.
+type WorkerPool struct {
+	jobStore  JobStore
+	taskStore *tasks.TaskStore
+	cfg       *config.WorkerPool
+	logger    *zerolog.Logger
+}
+
+// New builds a worker pool ready to be started with Run.
+func New(jobStore JobStore, taskStore *tasks.TaskStore, cfg *config.WorkerPool, logger *zerolog.Logger) *WorkerPool {
+	return &WorkerPool{
+		jobStore:  jobStore,
+		taskStore: taskStore,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Run blocks, dispatching work to the registered tasks until ctx is
+// cancelled.
+func (wp *WorkerPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wp.runJobs(ctx)
+
+			for _, task := range wp.taskStore.Tasks() {
+				if err := task.Run(ctx); err != nil {
+					wp.logger.Error().Msg(err.Error())
+				}
+			}
+		}
+	}
+}
+
+// runJobs drains up to cfg.QueueSize pending jobs and hands each order
+// number to every registered task that implements JobRunner.
+func (wp *WorkerPool) runJobs(ctx context.Context) {
+	orderNumbers, err := wp.jobStore.NextJobs(ctx, wp.cfg.QueueSize)
+	if err != nil {
+		wp.logger.Error().Msg(err.Error())
+
+		return
+	}
+
+	for _, number := range orderNumbers {
+		for _, task := range wp.taskStore.Tasks() {
+			runner, ok := task.(JobRunner)
+			if !ok {
+				continue
+			}
+
+			if err := runner.RunJob(ctx, number); err != nil {
+				wp.logger.Error().Msg(err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingInterval   = pongWait * 9 / 10
+	sendBufferSize = 32
+)
+
+// Client is one connected WebSocket subscriber. Events are delivered through
+// a buffered channel so that a slow reader cannot block Hub.Publish for
+// other users (or other connections of the same user); if the buffer fills
+// up the connection is dropped instead.
+type Client struct {
+	conn   *websocket.Conn
+	logger *zerolog.Logger
+	out    chan Event
+	closed chan struct{}
+}
+
+// NewClient wraps conn for use with Hub.Subscribe.
+func NewClient(conn *websocket.Conn, logger *zerolog.Logger) *Client {
+	return &Client{
+		conn:   conn,
+		logger: logger,
+		out:    make(chan Event, sendBufferSize),
+		closed: make(chan struct{}),
+	}
+}
+
+// send enqueues event for delivery, dropping the connection if the client
+// isn't keeping up.
+func (c *Client) send(event Event) {
+	select {
+	case c.out <- event:
+	default:
+		c.logger.Warn().Msg("ws client send buffer full, dropping connection")
+		c.conn.Close()
+	}
+}
+
+// WritePump delivers queued events and ping keepalives to the client until
+// the connection is closed or ctx-like closed signal fires. It owns all
+// writes to conn, so it must be the only goroutine writing to it.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer c.conn.Close()
+
+	for {
+		select {
+		case event, ok := <-c.out:
+			if !ok {
+				return
+			}
+
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if err := c.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// ReadPump discards incoming messages (the client has nothing to say) but
+// must run so pong keepalives are processed and the read deadline is reset.
+// It returns when the connection is closed.
+func (c *Client) ReadPump() {
+	defer close(c.closed)
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		return nil
+	})
+
+	for {
+		if _, _, err := c.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
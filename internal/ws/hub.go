@@ -0,0 +1,79 @@
+// Package ws implements an in-process pub/sub fanned out per user, so that
+// order and balance changes can be pushed to connected WebSocket clients in
+// real time.
+package ws
+
+import "sync"
+
+// Event is a single notification pushed to a user's subscribers. ID is
+// monotonically increasing per user so a reconnecting client can report the
+// last ID it saw and detect gaps.
+type Event struct {
+	ID      uint64      `json:"id"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// Hub fans out events to every subscriber registered for a given user.
+type Hub struct {
+	mu sync.Mutex
+	// subscribers is pruned as connections close: the inner set, and then
+	// the outer key once it's empty, so the map doesn't grow unbounded over
+	// the life of the process.
+	subscribers map[string]map[*Client]struct{}
+	// lastEventID deliberately is NOT pruned when a user disconnects. IDs
+	// must stay monotonic across reconnects so a client that reports the
+	// last ID it saw can detect gaps; resetting it on disconnect would let a
+	// reconnecting client silently reuse IDs it already consumed.
+	lastEventID map[string]uint64
+}
+
+// NewHub builds an empty Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Client]struct{}),
+		lastEventID: make(map[string]uint64),
+	}
+}
+
+// Subscribe registers c to receive events published for userID. Call the
+// returned func to unsubscribe once the connection closes.
+func (h *Hub) Subscribe(userID string, c *Client) (unsubscribe func()) {
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Client]struct{})
+	}
+	h.subscribers[userID][c] = struct{}{}
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		delete(h.subscribers[userID], c)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Publish delivers an event of the given type and payload to every
+// subscriber currently registered for userID. Slow subscribers are
+// disconnected rather than allowed to block other users; see Client.send.
+func (h *Hub) Publish(userID, eventType string, payload interface{}) {
+	h.mu.Lock()
+	h.lastEventID[userID]++
+	event := Event{
+		ID:      h.lastEventID[userID],
+		Type:    eventType,
+		Payload: payload,
+	}
+	subscribers := make([]*Client, 0, len(h.subscribers[userID]))
+	for c := range h.subscribers[userID] {
+		subscribers = append(subscribers, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range subscribers {
+		c.send(event)
+	}
+}